@@ -0,0 +1,244 @@
+package dogstatsd
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Default MTUs used when Config.Buffered is true and Config.MTU is zero.
+const (
+	DefaultUDPMTU = 1432
+	DefaultUDSMTU = 8192
+)
+
+// DefaultFlushInterval is used when Config.Buffered is true and
+// Config.FlushInterval is zero.
+const DefaultFlushInterval = 100 * time.Millisecond
+
+// BufferedTransport wraps another Transport, coalescing writes into
+// newline-separated payloads of up to MTU bytes instead of writing each one
+// to the network individually. It flushes automatically when the buffer
+// would exceed its MTU or message count limit, on a FlushInterval timer, and
+// whenever Flush or Close is called.
+type BufferedTransport struct {
+	next                  Transport
+	mtu                   int
+	maxMessagesPerPayload int
+	flushInterval         time.Duration
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	bufMsgs int
+
+	stop chan struct{}
+	done chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewBufferedTransport returns a BufferedTransport that flushes to next.
+// mtu <= 0 selects DefaultUDPMTU, flushInterval <= 0 selects
+// DefaultFlushInterval, and maxMessagesPerPayload <= 0 leaves the message
+// count per payload unbounded.
+func NewBufferedTransport(next Transport, mtu, maxMessagesPerPayload int, flushInterval time.Duration) *BufferedTransport {
+	if mtu <= 0 {
+		mtu = DefaultUDPMTU
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+
+	t := &BufferedTransport{
+		next:                  next,
+		mtu:                   mtu,
+		maxMessagesPerPayload: maxMessagesPerPayload,
+		flushInterval:         flushInterval,
+		stop:                  make(chan struct{}),
+		done:                  make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+// Write appends payload to the pending batch, flushing first if it wouldn't
+// fit within the configured MTU or message count.
+func (t *BufferedTransport) Write(payload []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.appendLocked(payload)
+}
+
+// appendLocked appends payload to the pending batch. The caller must hold
+// mu.
+func (t *BufferedTransport) appendLocked(payload []byte) error {
+	needed := len(payload)
+	if t.buf.Len() > 0 {
+		needed++ // newline separator
+	}
+	if t.buf.Len() > 0 && t.buf.Len()+needed > t.mtu {
+		if err := t.flushLocked(); err != nil {
+			return err
+		}
+	}
+	if t.buf.Len() > 0 {
+		t.buf.WriteByte('\n')
+	}
+	t.buf.Write(payload)
+	t.bufMsgs++
+
+	if t.maxMessagesPerPayload > 0 && t.bufMsgs >= t.maxMessagesPerPayload {
+		return t.flushLocked()
+	}
+	return nil
+}
+
+// flushLocked sends the pending batch to next, if any. The caller must hold
+// mu.
+func (t *BufferedTransport) flushLocked() error {
+	if t.buf.Len() == 0 {
+		return nil
+	}
+	payload := append([]byte(nil), t.buf.Bytes()...)
+	t.buf.Reset()
+	t.bufMsgs = 0
+	return t.next.Write(payload)
+}
+
+// Flush immediately writes the pending batch to next.
+func (t *BufferedTransport) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.flushLocked()
+}
+
+// Close stops the background flush goroutine, flushes whatever is pending,
+// and closes next. Calling Close more than once is safe; only the first
+// call does any work.
+func (t *BufferedTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.stop)
+		<-t.done
+		t.Flush()
+		t.closeErr = t.next.Close()
+	})
+	return t.closeErr
+}
+
+// loop periodically flushes the buffer until stop is closed.
+func (t *BufferedTransport) loop() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.Flush()
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// gaugeAgg holds the last value seen for a gauge between flushes.
+type gaugeAgg struct {
+	name      string
+	tagSuffix string
+	value     float64
+}
+
+// countAgg holds the running sum for a counter between flushes.
+type countAgg struct {
+	name      string
+	tagSuffix string
+	value     int64
+}
+
+// setAgg holds the deduplicated values seen for a set between flushes.
+type setAgg struct {
+	name      string
+	tagSuffix string
+	values    map[string]struct{}
+}
+
+// emit hands a single rendered metric/event line off to the transport.
+func (c *Client) emit(line string) error {
+	return c.sendString(line)
+}
+
+// flushAggregates renders the current aggregated gauges, counts and sets to
+// the transport and resets the aggregation state, returning the first error
+// encountered, if any. It always attempts every pending metric, even after
+// an error, so one failing write doesn't hold back the rest of the batch.
+func (c *Client) flushAggregates() error {
+	if !c.aggregate {
+		return nil
+	}
+	c.aggMu.Lock()
+	defer c.aggMu.Unlock()
+
+	var firstErr error
+	record := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, g := range c.gauges {
+		record(c.emit(fmt.Sprintf("%s:%f|g%s", g.name, g.value, g.tagSuffix)))
+	}
+	for _, ct := range c.counts {
+		record(c.emit(fmt.Sprintf("%s:%d|c%s", ct.name, ct.value, ct.tagSuffix)))
+	}
+	for _, s := range c.sets {
+		for value := range s.values {
+			record(c.emit(fmt.Sprintf("%s:%s|s%s", s.name, value, s.tagSuffix)))
+		}
+	}
+
+	c.gauges = make(map[string]*gaugeAgg)
+	c.counts = make(map[string]*countAgg)
+	c.sets = make(map[string]*setAgg)
+	return firstErr
+}
+
+func (c *Client) recordGauge(name string, value float64, tags []string) {
+	fullName := c.namespace + name
+	tagSuffix := c.tagString(tags)
+	key := fullName + tagSuffix
+
+	c.aggMu.Lock()
+	defer c.aggMu.Unlock()
+	c.gauges[key] = &gaugeAgg{name: fullName, tagSuffix: tagSuffix, value: value}
+}
+
+func (c *Client) recordCount(name string, value int64, tags []string) {
+	fullName := c.namespace + name
+	tagSuffix := c.tagString(tags)
+	key := fullName + tagSuffix
+
+	c.aggMu.Lock()
+	defer c.aggMu.Unlock()
+	if ct, ok := c.counts[key]; ok {
+		ct.value += value
+		return
+	}
+	c.counts[key] = &countAgg{name: fullName, tagSuffix: tagSuffix, value: value}
+}
+
+func (c *Client) recordSet(name string, value string, tags []string) {
+	fullName := c.namespace + name
+	tagSuffix := c.tagString(tags)
+	key := fullName + tagSuffix
+
+	c.aggMu.Lock()
+	defer c.aggMu.Unlock()
+	s, ok := c.sets[key]
+	if !ok {
+		s = &setAgg{name: fullName, tagSuffix: tagSuffix, values: make(map[string]struct{})}
+		c.sets[key] = s
+	}
+	s.values[value] = struct{}{}
+}
@@ -0,0 +1,122 @@
+package dogstatsd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// failingTransport always fails Write, to exercise error propagation out of
+// Client.Flush/Close.
+type failingTransport struct {
+	err error
+}
+
+func (t *failingTransport) Write(payload []byte) error { return t.err }
+func (t *failingTransport) Close() error               { return nil }
+
+func newBufferedClient(t *testing.T, addr string, cfg Config) *Client {
+	cfg.Addr = addr
+	cfg.Buffered = true
+	client, err := NewWithConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClientBuffered(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	client := newBufferedClient(t, server.LocalAddr().String(), Config{})
+	defer client.Close()
+
+	if err := client.Gauge("test.gauge", 1, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Count("test.count", 2, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	message := serverRead(t, server)
+	lines := strings.Split(message, "\n")
+	if expect := []string{"test.gauge:1.000000|g", "test.count:2|c"}; lines[0] != expect[0] || lines[1] != expect[1] {
+		t.Errorf("Expected:\t%v\nActual:\t\t%v", expect, lines)
+	}
+}
+
+func TestClientBufferedAggregate(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	client := newBufferedClient(t, server.LocalAddr().String(), Config{Aggregate: true})
+	defer client.Close()
+
+	if err := client.Gauge("test.gauge", 1, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Gauge("test.gauge", 2, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Count("test.count", 1, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Count("test.count", 4, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set("test.set", "a", nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set("test.set", "a", nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	message := serverRead(t, server)
+	if want := "test.gauge:2.000000|g"; !strings.Contains(message, want) {
+		t.Errorf("Expected gauge to keep last value: %q not in %q", want, message)
+	}
+	if want := "test.count:5|c"; !strings.Contains(message, want) {
+		t.Errorf("Expected count to be summed: %q not in %q", want, message)
+	}
+	if want := "test.set:a|s"; strings.Count(message, want) != 1 {
+		t.Errorf("Expected set to be deduplicated, got %q", message)
+	}
+}
+
+func TestClientBufferedCloseTwice(t *testing.T) {
+	mem := NewMemoryTransport()
+	client, err := NewWithConfig(&Config{Transport: mem, Buffered: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlushAggregatesError(t *testing.T) {
+	wantErr := errors.New("write failed")
+	client, err := NewWithConfig(&Config{Transport: &failingTransport{err: wantErr}, Buffered: true, Aggregate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.Count("test.count", 1, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Flush(); err != wantErr {
+		t.Errorf("Expected Flush to surface the transport error, got %v", err)
+	}
+}
@@ -0,0 +1,494 @@
+// Copyright 2013 Ooyala, Inc.
+
+// Package dogstatsd provides a client for sending metrics and events to a
+// DogStatsD server (typically the Datadog agent) over UDP or a Unix
+// datagram socket.
+package dogstatsd
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultMaxEventBytes is the maximum size, in bytes, of an event payload
+// accepted by a stock Datadog agent. Events larger than a Client's limit are
+// discarded rather than truncated, since truncation would corrupt the
+// event's length-prefixed wire format.
+const DefaultMaxEventBytes = 8 << 10
+
+// MinMaxEventBytes and MaxMaxEventBytes bound the values accepted by
+// Client.SetMaxEventBytes and Config.MaxEventBytes; anything outside this
+// range is clamped.
+const (
+	MinMaxEventBytes = 1 << 10
+	MaxMaxEventBytes = 64 << 10
+)
+
+// EventTooBigError is returned by Client.Event (and the Warning/Error/
+// Info/Success helpers) when an event's payload exceeds the client's
+// configured max event size, and identifies the discarded event.
+type EventTooBigError struct {
+	Title string // full event title, including namespace
+	Size  int    // actual payload size, in bytes
+	Limit int    // the client's configured limit, in bytes
+}
+
+func (e *EventTooBigError) Error() string {
+	return fmt.Sprintf("Event %q payload is too big (%d bytes, max %d), event discarded", e.Title, e.Size, e.Limit)
+}
+
+// Network names accepted by Config.Network / NewWithConfig.
+const (
+	NetworkUDP       = "udp"
+	NetworkUnixgram  = "unixgram"
+	unixSchemePrefix = "unix://"
+)
+
+// Config holds the parameters needed to build a Client.
+type Config struct {
+	// Addr is the destination address. For UDP it is a "host:port" pair.
+	// For Unix datagram sockets it is a filesystem path, optionally
+	// prefixed with "unix://". Ignored if Transport is set.
+	Addr string
+
+	// Network selects the transport: NetworkUDP or NetworkUnixgram. If
+	// empty, it is inferred from Addr: an "unix://" prefix selects
+	// NetworkUnixgram, anything else selects NetworkUDP. Ignored if
+	// Transport is set.
+	Network string
+
+	// WriteTimeout bounds how long a single Write to the socket may
+	// block before it is aborted. It only applies to NetworkUnixgram,
+	// since UDP writes do not block on a connected loopback/agent peer.
+	// Zero means no deadline. Ignored if Transport is set.
+	WriteTimeout time.Duration
+
+	// Transport, if set, is used in place of the UDP/UDS transport that
+	// would otherwise be built from Addr/Network/WriteTimeout. This is how
+	// a MemoryTransport, OTLPTransport or MultiTransport is plugged in.
+	Transport Transport
+
+	// Buffered, when true, causes Gauge/Count/Histogram/Set/Event calls to
+	// be appended to an internal buffer instead of written to the
+	// transport immediately. See Client.Flush for when the buffer is sent.
+	Buffered bool
+
+	// MTU bounds the size, in bytes, of a single flushed payload. Zero
+	// selects DefaultUDPMTU or DefaultUDSMTU depending on Network. Only
+	// meaningful when Buffered is true.
+	MTU int
+
+	// FlushInterval is how often the buffer is flushed by a background
+	// goroutine. Zero selects DefaultFlushInterval. Only meaningful when
+	// Buffered is true.
+	FlushInterval time.Duration
+
+	// MaxMessagesPerPayload caps the number of metrics packed into a
+	// single flushed payload, in addition to the MTU limit. Zero means
+	// unbounded. Only meaningful when Buffered is true.
+	MaxMessagesPerPayload int
+
+	// Aggregate enables in-process aggregation on top of buffering:
+	// gauges keep their last value, counts are summed, and sets are
+	// deduplicated, with the aggregated snapshot emitted on each flush.
+	// Histograms, distributions and timings are always forwarded
+	// per-sample. Only meaningful when Buffered is true.
+	Aggregate bool
+
+	// MaxEventBytes is the largest event payload the client will send
+	// before discarding it; see Client.SetMaxEventBytes. Zero selects
+	// DefaultMaxEventBytes. Values outside [MinMaxEventBytes,
+	// MaxMaxEventBytes] are clamped.
+	MaxEventBytes int
+
+	// RandSource seeds the client's sampling decisions (see Gauge/Count/
+	// Histogram/Distribution/Timing/Set's rate parameter). Nil selects a
+	// source seeded from the current time; tests that need deterministic
+	// sampling should supply their own, e.g. rand.NewSource(1).
+	RandSource rand.Source
+}
+
+// Client is a DogStatsD client. A Client is safe for concurrent use.
+type Client struct {
+	namespace string
+	tags      []string
+
+	transport     Transport
+	maxEventBytes int
+
+	aggregate bool
+	aggMu     sync.Mutex
+	gauges    map[string]*gaugeAgg
+	counts    map[string]*countAgg
+	sets      map[string]*setAgg
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+
+	closeOnce sync.Once
+}
+
+// New returns a new Client that sends metrics to addr. addr is a "host:port"
+// UDP address, or an "unix:///path/to/socket" address to send over a Unix
+// datagram socket instead.
+func New(addr string) (*Client, error) {
+	return NewWithConfig(&Config{Addr: addr})
+}
+
+// NewUDS returns a new Client that sends metrics over the Unix datagram
+// socket at socketPath.
+func NewUDS(socketPath string) (*Client, error) {
+	return NewWithConfig(&Config{Addr: socketPath, Network: NetworkUnixgram})
+}
+
+// NewWithConfig returns a new Client configured from cfg.
+func NewWithConfig(cfg *Config) (*Client, error) {
+	transport := cfg.Transport
+	network := cfg.Network
+	if transport == nil {
+		addr := cfg.Addr
+		if network == "" {
+			if strings.HasPrefix(addr, unixSchemePrefix) {
+				network = NetworkUnixgram
+			} else {
+				network = NetworkUDP
+			}
+		}
+		addr = strings.TrimPrefix(addr, unixSchemePrefix)
+
+		var err error
+		if network == NetworkUnixgram {
+			transport, err = NewUDSTransport(addr, cfg.WriteTimeout)
+		} else {
+			transport, err = NewUDPTransport(addr)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Buffered {
+		mtu := cfg.MTU
+		if mtu == 0 {
+			if network == NetworkUnixgram {
+				mtu = DefaultUDSMTU
+			} else {
+				mtu = DefaultUDPMTU
+			}
+		}
+		transport = NewBufferedTransport(transport, mtu, cfg.MaxMessagesPerPayload, cfg.FlushInterval)
+	}
+
+	randSource := cfg.RandSource
+	if randSource == nil {
+		randSource = rand.NewSource(time.Now().UnixNano())
+	}
+
+	c := &Client{
+		transport:     transport,
+		aggregate:     cfg.Buffered && cfg.Aggregate,
+		maxEventBytes: clampMaxEventBytes(cfg.MaxEventBytes),
+		rand:          rand.New(randSource),
+	}
+	if c.aggregate {
+		c.gauges = make(map[string]*gaugeAgg)
+		c.counts = make(map[string]*countAgg)
+		c.sets = make(map[string]*setAgg)
+	}
+	return c, nil
+}
+
+// SetGlobalNamespace sets a prefix to prepend to every metric name and
+// event title sent by the client.
+func (c *Client) SetGlobalNamespace(namespace string) {
+	c.namespace = namespace
+}
+
+// SetGlobalTags sets tags that are appended to every metric and event sent
+// by the client, in addition to any tags passed to individual calls.
+func (c *Client) SetGlobalTags(tags []string) {
+	c.tags = tags
+}
+
+// SetMaxEventBytes sets the largest event payload the client will send
+// before discarding it and returning an *EventTooBigError. Zero selects
+// DefaultMaxEventBytes; any other n is clamped into [MinMaxEventBytes,
+// MaxMaxEventBytes].
+func (c *Client) SetMaxEventBytes(n int) {
+	c.maxEventBytes = clampMaxEventBytes(n)
+}
+
+// clampMaxEventBytes clamps n into the allowed range, treating zero as "use
+// the default".
+func clampMaxEventBytes(n int) int {
+	if n == 0 {
+		return DefaultMaxEventBytes
+	}
+	if n < MinMaxEventBytes {
+		return MinMaxEventBytes
+	}
+	if n > MaxMaxEventBytes {
+		return MaxMaxEventBytes
+	}
+	return n
+}
+
+// Close drains any buffered metrics with a final Flush and releases the
+// underlying transport. Calling Close more than once is safe; only the
+// first call does any work.
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.Flush()
+		err = c.transport.Close()
+	})
+	return err
+}
+
+// Flush immediately writes any buffered metrics, including a snapshot of
+// aggregated gauges/counts/sets if aggregation is enabled, to the
+// transport. It is a no-op on a Client whose transport does not buffer.
+func (c *Client) Flush() error {
+	aggErr := c.flushAggregates()
+	var flushErr error
+	if f, ok := c.transport.(Flusher); ok {
+		flushErr = f.Flush()
+	}
+	if aggErr != nil {
+		return aggErr
+	}
+	return flushErr
+}
+
+func (c *Client) sendString(s string) error {
+	return c.transport.Write([]byte(s))
+}
+
+// tagString appends the client's global tags to tags and renders them as
+// "|#tag1,tag2", or "" if there are none.
+func (c *Client) tagString(tags []string) string {
+	all := tags
+	if len(c.tags) > 0 {
+		all = make([]string, 0, len(tags)+len(c.tags))
+		all = append(all, tags...)
+		all = append(all, c.tags...)
+	}
+	if len(all) == 0 {
+		return ""
+	}
+	return "|#" + strings.Join(all, ",")
+}
+
+func (c *Client) rateString(rate float64) string {
+	if rate == 1 {
+		return ""
+	}
+	return "|@" + strconv.FormatFloat(rate, 'f', 6, 64)
+}
+
+// shouldSend reports whether a call sampled at rate should actually be
+// sent. rate >= 1 always sends; otherwise the call is sent with probability
+// rate, using the client's RandSource.
+func (c *Client) shouldSend(rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	c.randMu.Lock()
+	defer c.randMu.Unlock()
+	return c.rand.Float64() < rate
+}
+
+// Gauge records the current value of a metric. If the client has
+// aggregation enabled, only the last value of each call between flushes is
+// kept rather than sending one payload per call, and rate is ignored for
+// the same reason as Count/Set: the aggregate already bounds the number of
+// payloads sent, and sampling the calls that feed it would silently drop
+// updates with no way for the server to recover them.
+func (c *Client) Gauge(name string, value float64, tags []string, rate float64) error {
+	if c.aggregate {
+		c.recordGauge(name, value, tags)
+		return nil
+	}
+	if !c.shouldSend(rate) {
+		return nil
+	}
+	return c.emit(fmt.Sprintf("%s%s:%f|g%s%s", c.namespace, name, value, c.rateString(rate), c.tagString(tags)))
+}
+
+// Count increments (or decrements, for a negative value) a counter. If the
+// client has aggregation enabled, calls between flushes are summed rather
+// than sending one payload per call, and rate is ignored: the aggregate
+// already bounds the number of payloads sent, and sampling the calls that
+// feed it would silently scale down the summed value with no way for the
+// server to rescale it back.
+func (c *Client) Count(name string, value int64, tags []string, rate float64) error {
+	if c.aggregate {
+		c.recordCount(name, value, tags)
+		return nil
+	}
+	if !c.shouldSend(rate) {
+		return nil
+	}
+	return c.emit(fmt.Sprintf("%s%s:%d|c%s%s", c.namespace, name, value, c.rateString(rate), c.tagString(tags)))
+}
+
+// Histogram samples a value into a histogram. Histogram samples are always
+// forwarded individually, even with aggregation enabled.
+func (c *Client) Histogram(name string, value float64, tags []string, rate float64) error {
+	if !c.shouldSend(rate) {
+		return nil
+	}
+	return c.emit(fmt.Sprintf("%s%s:%f|h%s%s", c.namespace, name, value, c.rateString(rate), c.tagString(tags)))
+}
+
+// Distribution samples a value into a distribution. Unlike Histogram,
+// distribution percentiles are aggregated globally by the agent rather than
+// per host, at the cost of higher backend overhead. Distribution samples
+// are always forwarded individually, even with aggregation enabled.
+func (c *Client) Distribution(name string, value float64, tags []string, rate float64) error {
+	if !c.shouldSend(rate) {
+		return nil
+	}
+	return c.emit(fmt.Sprintf("%s%s:%f|d%s%s", c.namespace, name, value, c.rateString(rate), c.tagString(tags)))
+}
+
+// Timing samples a duration into a histogram, converting it to
+// milliseconds. Timing samples are always forwarded individually, even
+// with aggregation enabled.
+func (c *Client) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	if !c.shouldSend(rate) {
+		return nil
+	}
+	ms := float64(value) / float64(time.Millisecond)
+	return c.emit(fmt.Sprintf("%s%s:%f|ms%s%s", c.namespace, name, ms, c.rateString(rate), c.tagString(tags)))
+}
+
+// Set adds value to a set, which counts the number of unique values seen.
+// If the client has aggregation enabled, duplicate values for the same set
+// between flushes are sent only once, and rate is ignored for the same
+// reason as Count: deduplication already bounds the payload, and sampling
+// beforehand would drop distinct values with no way for the server to
+// recover them.
+func (c *Client) Set(name string, value string, tags []string, rate float64) error {
+	if c.aggregate {
+		c.recordSet(name, value, tags)
+		return nil
+	}
+	if !c.shouldSend(rate) {
+		return nil
+	}
+	return c.emit(fmt.Sprintf("%s%s:%s|s%s%s", c.namespace, name, value, c.rateString(rate), c.tagString(tags)))
+}
+
+// Priority is the value of an event's "priority" field.
+type Priority string
+
+// Valid event priorities.
+const (
+	Normal Priority = "normal"
+	Low    Priority = "low"
+)
+
+// AlertType is the value of an event's "alert type" field.
+type AlertType string
+
+// Valid event alert types.
+const (
+	Success AlertType = "success"
+	Info    AlertType = "info"
+	Warning AlertType = "warning"
+	Error   AlertType = "error"
+)
+
+// EventOpts holds the optional fields of an event.
+type EventOpts struct {
+	DateHappened   time.Time
+	Priority       Priority
+	Host           string
+	AggregationKey string
+	SourceTypeName string
+	AlertType      AlertType
+	Tags           []string
+}
+
+// Event sends an event with the given title, text, and options.
+func (c *Client) Event(title, text string, opts *EventOpts) error {
+	if opts == nil {
+		opts = &EventOpts{}
+	}
+
+	fullTitle := c.namespace + title
+	if size := len(fullTitle) + len(text); size > c.maxEventBytes {
+		return &EventTooBigError{Title: fullTitle, Size: size, Limit: c.maxEventBytes}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "_e{%d,%d}:%s|%s", utf8.RuneCountInString(fullTitle), utf8.RuneCountInString(text), fullTitle, text)
+	if opts.AlertType != "" {
+		fmt.Fprintf(&b, "|t:%s", opts.AlertType)
+	}
+	if opts.SourceTypeName != "" {
+		fmt.Fprintf(&b, "|s:%s", opts.SourceTypeName)
+	}
+	if !opts.DateHappened.IsZero() {
+		fmt.Fprintf(&b, "|d:%d", opts.DateHappened.Unix())
+	}
+	if opts.Priority != "" {
+		fmt.Fprintf(&b, "|p:%s", opts.Priority)
+	}
+	if opts.Host != "" {
+		fmt.Fprintf(&b, "|h:%s", opts.Host)
+	}
+	if opts.AggregationKey != "" {
+		fmt.Fprintf(&b, "|k:%s", opts.AggregationKey)
+	}
+
+	tags := opts.Tags
+	if ns := strings.TrimSuffix(c.namespace, "."); ns != "" {
+		tags = append(append([]string{}, tags...), ns)
+	}
+	if s := c.tagString(tags); s != "" {
+		b.WriteString(s)
+	}
+
+	return c.emit(b.String())
+}
+
+// alertEvent sends an event via the Warning/Error/Info/Success helpers,
+// which derive the event's source type from the global namespace and tag
+// it with "<namespace>-<alertType>" in addition to the namespace itself.
+func (c *Client) alertEvent(alertType AlertType, title, text string, tags []string) error {
+	ns := strings.TrimSuffix(c.namespace, ".")
+	autoTags := append(append([]string{}, tags...), fmt.Sprintf("%s-%s", ns, alertType))
+	return c.Event(title, text, &EventOpts{
+		AlertType:      alertType,
+		SourceTypeName: ns,
+		Tags:           autoTags,
+	})
+}
+
+// Warning sends a warning event.
+func (c *Client) Warning(title, text string, tags []string) error {
+	return c.alertEvent(Warning, title, text, tags)
+}
+
+// Error sends an error event.
+func (c *Client) Error(title, text string, tags []string) error {
+	return c.alertEvent(Error, title, text, tags)
+}
+
+// Info sends an info event.
+func (c *Client) Info(title, text string, tags []string) error {
+	return c.alertEvent(Info, title, text, tags)
+}
+
+// Success sends a success event.
+func (c *Client) Success(title, text string, tags []string) error {
+	return c.alertEvent(Success, title, text, tags)
+}
@@ -4,6 +4,7 @@ package dogstatsd
 
 import (
 	"bytes"
+	"math/rand"
 	"net"
 	"testing"
 	"time"
@@ -27,23 +28,27 @@ var dogstatsdTests = []struct {
 	{"", nil, (*Client).Count, "test.count", int64(1), []string{"tagA"}, 1.0, "test.count:1|c|#tagA"},
 	{"", nil, (*Client).Count, "test.count", int64(-1), []string{"tagA"}, 1.0, "test.count:-1|c|#tagA"},
 	{"", nil, (*Client).Histogram, "test.histogram", 2.3, []string{"tagA"}, 1.0, "test.histogram:2.300000|h|#tagA"},
+	{"", nil, (*Client).Distribution, "test.distribution", 2.3, []string{"tagA"}, 1.0, "test.distribution:2.300000|d|#tagA"},
+	{"", nil, (*Client).Distribution, "test.distribution", 2.3, []string{"tagA"}, 0.5, "test.distribution:2.300000|d|@0.500000|#tagA"},
+	{"", nil, (*Client).Timing, "test.timing", 1500 * time.Millisecond, []string{"tagA"}, 1.0, "test.timing:1500.000000|ms|#tagA"},
 	{"", nil, (*Client).Set, "test.set", "uuid", []string{"tagA"}, 1.0, "test.set:uuid|s|#tagA"},
 	{"flubber.", nil, (*Client).Set, "test.set", "uuid", []string{"tagA"}, 1.0, "flubber.test.set:uuid|s|#tagA"},
 	{"", []string{"tagC"}, (*Client).Set, "test.set", "uuid", []string{"tagA"}, 1.0, "test.set:uuid|s|#tagA,tagC"},
 }
 
 func TestClient(t *testing.T) {
-	server := newServer(t)
-	defer server.Close()
-
-	client := newClient(t, server.LocalAddr().String())
+	mem := NewMemoryTransport()
+	client, err := NewWithConfig(&Config{Transport: mem, RandSource: rand.NewSource(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
 	defer client.Close()
 
 	for i, tt := range dogstatsdTests {
 		client.SetGlobalNamespace(tt.GlobalNamespace)
 		client.SetGlobalTags(tt.GlobalTags)
 
-		var err error
+		mem.Reset()
 		switch fct := tt.Method.(type) {
 		// Gauge, Histogram
 		case func(*Client, string, float64, []string, float64) error:
@@ -54,6 +59,9 @@ func TestClient(t *testing.T) {
 		// Set
 		case func(*Client, string, string, []string, float64) error:
 			err = fct(client, tt.Metric, tt.Value.(string), tt.Tags, tt.Rate)
+		// Timing
+		case func(*Client, string, time.Duration, []string, float64) error:
+			err = fct(client, tt.Metric, tt.Value.(time.Duration), tt.Tags, tt.Rate)
 		default:
 			t.Fatalf("Unkown method type: %T", fct)
 		}
@@ -61,7 +69,11 @@ func TestClient(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		if message := serverRead(t, server); message != tt.Expected {
+		payloads := mem.Payloads()
+		if len(payloads) != 1 {
+			t.Fatalf("[%d] Expected 1 payload, got %d", i, len(payloads))
+		}
+		if message := string(payloads[0]); message != tt.Expected {
 			t.Errorf("\n[%d] Expected:\t%s\nActual:\t\t%s", i, tt.Expected, message)
 		}
 	}
@@ -112,28 +124,62 @@ var eventTests = []eventTest{
 }
 
 func TestEvent(t *testing.T) {
-	server := newServer(t)
-	defer server.Close()
-
-	client := newClient(t, server.LocalAddr().String())
+	mem := NewMemoryTransport()
+	client, err := NewWithConfig(&Config{Transport: mem})
+	if err != nil {
+		t.Fatal(err)
+	}
 	client.SetGlobalNamespace("flubber.")
 	defer client.Close()
 
 	for i, tt := range eventTests {
+		mem.Reset()
 		if err := tt.logEvent(client); err != nil {
 			t.Fatal(err)
 		}
-		if message := serverRead(t, server); message != tt.expected {
+		payloads := mem.Payloads()
+		if len(payloads) != 1 {
+			t.Fatalf("[%d] Expected 1 payload, got %d", i, len(payloads))
+		}
+		if message := string(payloads[0]); message != tt.expected {
 			t.Errorf("\n[%d] Expected:\t%s\nActual:\t\t%s", i, tt.expected, message)
 		}
 	}
 
 	b := bytes.NewBuffer(nil)
-	b.Write(bytes.Repeat([]byte("a"), maxEventBytes+1))
-	if err := client.Error("too long", b.String(), []string{}); err == nil {
+	b.Write(bytes.Repeat([]byte("a"), DefaultMaxEventBytes+1))
+	err = client.Error("too long", b.String(), []string{})
+	if err == nil {
+		t.Fatal("Expected error due to exceeded event byte length")
+	}
+	tooBig, ok := err.(*EventTooBigError)
+	if !ok {
+		t.Fatalf("Expected *EventTooBigError, got %T: %v", err, err)
+	}
+	if tooBig.Title != "flubber.too long" || tooBig.Limit != DefaultMaxEventBytes {
+		t.Errorf("Unexpected error fields: %+v", tooBig)
+	}
+}
+
+func TestEventMaxBytes(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	client := newClient(t, server.LocalAddr().String())
+	defer client.Close()
+
+	client.SetMaxEventBytes(MinMaxEventBytes - 1) // clamped up to MinMaxEventBytes
+	if err := client.Info("short", "text", nil); err != nil {
+		t.Fatal(err)
+	}
+	serverRead(t, server)
+
+	b := bytes.NewBuffer(nil)
+	b.Write(bytes.Repeat([]byte("a"), MinMaxEventBytes))
+	if err := client.Info("big", b.String(), nil); err == nil {
 		t.Fatal("Expected error due to exceeded event byte length")
-	} else if expect, got := err.Error(), "Event \"flubber.too long\" payload is too big (more that 8KB), event discarded"; expect != got {
-		t.Errorf("Unexpected error message.\nExpect:\t%s\nGot:\t%s", expect, got)
+	} else if tooBig, ok := err.(*EventTooBigError); !ok || tooBig.Limit != MinMaxEventBytes {
+		t.Errorf("Unexpected error: %v", err)
 	}
 }
 
@@ -0,0 +1,115 @@
+package dogstatsd
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMemoryClient(t *testing.T, cfg Config) (*Client, *MemoryTransport) {
+	mem := NewMemoryTransport()
+	cfg.Transport = mem
+	client, err := NewWithConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, mem
+}
+
+func TestSamplingProbability(t *testing.T) {
+	const n = 10000
+	const rate = 0.25
+
+	client, mem := newMemoryClient(t, Config{RandSource: rand.NewSource(1)})
+	defer client.Close()
+
+	for i := 0; i < n; i++ {
+		if err := client.Count("test.count", 1, nil, rate); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := float64(len(mem.Payloads())) / n
+	if diff := got - rate; diff < -0.02 || diff > 0.02 {
+		t.Errorf("Expected sampling rate close to %v, got %v", rate, got)
+	}
+}
+
+func TestSamplingRateSuffix(t *testing.T) {
+	client, mem := newMemoryClient(t, Config{RandSource: rand.NewSource(1)})
+	defer client.Close()
+
+	checks := []struct {
+		name string
+		send func() error
+	}{
+		{"Gauge", func() error { return client.Gauge("test.gauge", 1, nil, 0.5) }},
+		{"Count", func() error { return client.Count("test.count", 1, nil, 0.5) }},
+		{"Histogram", func() error { return client.Histogram("test.histogram", 1, nil, 0.5) }},
+		{"Distribution", func() error { return client.Distribution("test.distribution", 1, nil, 0.5) }},
+		{"Timing", func() error { return client.Timing("test.timing", time.Second, nil, 0.5) }},
+		{"Set", func() error { return client.Set("test.set", "v", nil, 0.5) }},
+	}
+
+	for _, check := range checks {
+		mem.Reset()
+		// rate < 1 is probabilistic, so retry until the sample actually sends.
+		for i := 0; i < 1000 && len(mem.Payloads()) == 0; i++ {
+			if err := check.send(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		payloads := mem.Payloads()
+		if len(payloads) == 0 {
+			t.Fatalf("%s: never sampled in", check.name)
+		}
+		if !strings.Contains(string(payloads[len(payloads)-1]), "|@0.500000") {
+			t.Errorf("%s: expected rate suffix in %q", check.name, payloads[len(payloads)-1])
+		}
+	}
+}
+
+// TestSamplingIgnoredWhenAggregated verifies that Gauge, Count and Set
+// ignore rate entirely when aggregation is enabled, since the aggregate
+// already bounds the number of payloads sent and sampling beforehand would
+// silently drop (Gauge, Set) or scale down (Count) data with no way for the
+// server to recover it.
+func TestSamplingIgnoredWhenAggregated(t *testing.T) {
+	client, mem := newMemoryClient(t, Config{RandSource: rand.NewSource(1), Buffered: true, Aggregate: true})
+	defer client.Close()
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		if err := client.Gauge("test.gauge", 2, nil, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Count("test.count", 1, nil, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Set("test.set", "a", nil, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, payload := range mem.Payloads() {
+		lines = append(lines, string(payload))
+	}
+	message := strings.Join(lines, "\n")
+	if want := "test.gauge:2.000000|g"; !strings.Contains(message, want) {
+		t.Errorf("Expected gauge to be recorded despite rate=0: %q not in %q", want, message)
+	}
+	if want := "test.count:100|c"; !strings.Contains(message, want) {
+		t.Errorf("Expected every call to be aggregated despite rate=0: %q not in %q", want, message)
+	}
+	if want := "test.set:a|s"; !strings.Contains(message, want) {
+		t.Errorf("Expected set value to be recorded despite rate=0: %q not in %q", want, message)
+	}
+	if strings.Contains(message, "|@") {
+		t.Errorf("Expected no rate suffix on aggregated output, got %q", message)
+	}
+}
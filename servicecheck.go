@@ -0,0 +1,62 @@
+package dogstatsd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ServiceCheckStatus is the status reported by a service check.
+type ServiceCheckStatus int
+
+// Valid service check statuses. Warn is named to avoid colliding with the
+// event AlertType Warning.
+const (
+	OK ServiceCheckStatus = iota
+	Warn
+	Critical
+	Unknown
+)
+
+// ServiceCheckOpts holds the optional fields of a service check.
+type ServiceCheckOpts struct {
+	Timestamp int64 // Unix time; zero omits the field
+	Hostname  string
+	Tags      []string
+	Message   string
+}
+
+// ServiceCheck reports the status of a service. It emits the
+// "_sc|name|status|d:timestamp|h:hostname|#tags|m:message" wire format.
+func (c *Client) ServiceCheck(name string, status ServiceCheckStatus, opts *ServiceCheckOpts) error {
+	if opts == nil {
+		opts = &ServiceCheckOpts{}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "_sc|%s%s|%d", c.namespace, name, status)
+	if opts.Timestamp != 0 {
+		fmt.Fprintf(&b, "|d:%d", opts.Timestamp)
+	}
+	if opts.Hostname != "" {
+		fmt.Fprintf(&b, "|h:%s", opts.Hostname)
+	}
+	if s := c.tagString(opts.Tags); s != "" {
+		b.WriteString(s)
+	}
+	if opts.Message != "" {
+		fmt.Fprintf(&b, "|m:%s", escapeServiceCheckMessage(opts.Message))
+	}
+
+	return c.emit(b.String())
+}
+
+// escapeServiceCheckMessage escapes characters that would otherwise corrupt
+// the service check wire format: newlines (which would be read as a new
+// datagram) and every "m:" (which would be read as the start of a new
+// field).
+func escapeServiceCheckMessage(message string) string {
+	message = strings.ReplaceAll(message, "\n", "\\n")
+	message = strings.ReplaceAll(message, "m:", "m\\:")
+	return message
+}
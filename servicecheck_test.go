@@ -0,0 +1,52 @@
+package dogstatsd
+
+import "testing"
+
+type serviceCheckTest struct {
+	check    func(*Client) error
+	expected string
+}
+
+var serviceCheckTests = []serviceCheckTest{
+	{
+		check:    func(c *Client) error { return c.ServiceCheck("service.check", OK, nil) },
+		expected: "_sc|flubber.service.check|0",
+	},
+	{
+		check:    func(c *Client) error { return c.ServiceCheck("service.check", Warn, nil) },
+		expected: "_sc|flubber.service.check|1",
+	},
+	{
+		check: func(c *Client) error {
+			return c.ServiceCheck("service.check", Critical, &ServiceCheckOpts{
+				Timestamp: 1411080960,
+				Hostname:  "node.example.com",
+				Tags:      []string{"tagA"},
+				Message:   "m: not ok\nretrying",
+			})
+		},
+		expected: "_sc|flubber.service.check|2|d:1411080960|h:node.example.com|#tagA|m:m\\: not ok\\nretrying",
+	},
+	{
+		check:    func(c *Client) error { return c.ServiceCheck("service.check", Unknown, nil) },
+		expected: "_sc|flubber.service.check|3",
+	},
+}
+
+func TestServiceCheck(t *testing.T) {
+	server := newServer(t)
+	defer server.Close()
+
+	client := newClient(t, server.LocalAddr().String())
+	client.SetGlobalNamespace("flubber.")
+	defer client.Close()
+
+	for i, tt := range serviceCheckTests {
+		if err := tt.check(client); err != nil {
+			t.Fatal(err)
+		}
+		if message := serverRead(t, server); message != tt.expected {
+			t.Errorf("\n[%d] Expected:\t%s\nActual:\t\t%s", i, tt.expected, message)
+		}
+	}
+}
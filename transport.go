@@ -0,0 +1,15 @@
+package dogstatsd
+
+// Transport is the interface a Client writes DogStatsD payloads through. A
+// payload is one or more newline-separated metric/event lines.
+type Transport interface {
+	Write(payload []byte) error
+	Close() error
+}
+
+// Flusher is implemented by transports that hold writes back and need an
+// explicit flush point, such as BufferedTransport. Client.Flush calls this
+// if the Client's configured Transport implements it.
+type Flusher interface {
+	Flush() error
+}
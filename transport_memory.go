@@ -0,0 +1,82 @@
+package dogstatsd
+
+import "sync"
+
+// MemoryTransport records every payload written to it instead of sending it
+// anywhere. It is meant for tests that want to assert on what a Client
+// would have sent without standing up a UDP/UDS listener.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	payloads [][]byte
+}
+
+// NewMemoryTransport returns an empty MemoryTransport.
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+// Write records a copy of payload.
+func (t *MemoryTransport) Write(payload []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.payloads = append(t.payloads, append([]byte(nil), payload...))
+	return nil
+}
+
+// Close is a no-op; the recorded payloads remain available afterwards.
+func (t *MemoryTransport) Close() error {
+	return nil
+}
+
+// Payloads returns a copy of every payload written so far, in order.
+func (t *MemoryTransport) Payloads() [][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([][]byte, len(t.payloads))
+	copy(out, t.payloads)
+	return out
+}
+
+// Reset discards any recorded payloads.
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.payloads = nil
+}
+
+// MultiTransport fans a single Write out to every one of its transports,
+// for shadow traffic during a migration off one backend onto another.
+type MultiTransport struct {
+	transports []Transport
+}
+
+// NewMultiTransport returns a MultiTransport that writes to every one of
+// transports.
+func NewMultiTransport(transports ...Transport) *MultiTransport {
+	return &MultiTransport{transports: transports}
+}
+
+// Write writes payload to every transport, returning the first error
+// encountered, if any. It always writes to all of them, even after an
+// error.
+func (t *MultiTransport) Write(payload []byte) error {
+	var firstErr error
+	for _, tr := range t.transports {
+		if err := tr.Write(payload); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close closes every transport, returning the first error encountered, if
+// any. It always closes all of them, even after an error.
+func (t *MultiTransport) Close() error {
+	var firstErr error
+	for _, tr := range t.transports {
+		if err := tr.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,15 @@
+package dogstatsd
+
+import "testing"
+
+func TestMultiTransport(t *testing.T) {
+	a, b := NewMemoryTransport(), NewMemoryTransport()
+	multi := NewMultiTransport(a, b)
+
+	if err := multi.Write([]byte("test.gauge:1.000000|g")); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Payloads()) != 1 || len(b.Payloads()) != 1 {
+		t.Fatalf("Expected both transports to receive the payload, got %d and %d", len(a.Payloads()), len(b.Payloads()))
+	}
+}
@@ -0,0 +1,124 @@
+package dogstatsd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// UDPTransport writes payloads to a connected UDP socket.
+type UDPTransport struct {
+	conn net.Conn
+}
+
+// NewUDPTransport dials a UDP socket to addr.
+func NewUDPTransport(addr string) (*UDPTransport, error) {
+	udpAddr, err := net.ResolveUDPAddr(NetworkUDP, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP(NetworkUDP, nil, udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &UDPTransport{conn: conn}, nil
+}
+
+// Write sends payload as a single UDP datagram.
+func (t *UDPTransport) Write(payload []byte) error {
+	_, err := t.conn.Write(payload)
+	return err
+}
+
+// Close releases the underlying socket.
+func (t *UDPTransport) Close() error {
+	return t.conn.Close()
+}
+
+// UDSTransport writes payloads to a Unix datagram socket. Since the agent
+// end of the socket can go away and come back (e.g. on a restart), writes
+// that fail with ECONNREFUSED, ENOTCONN, EPIPE or on a closed socket
+// trigger a single reconnect-and-retry before the error is surfaced to the
+// caller.
+type UDSTransport struct {
+	addr         string
+	writeTimeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewUDSTransport dials a Unix datagram socket at addr. writeTimeout bounds
+// how long a single Write may block before it is aborted; zero means no
+// deadline.
+func NewUDSTransport(addr string, writeTimeout time.Duration) (*UDSTransport, error) {
+	t := &UDSTransport{addr: addr, writeTimeout: writeTimeout}
+	if err := t.connect(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// connect (re)dials the socket. The caller must hold mu.
+func (t *UDSTransport) connect() error {
+	raddr, err := net.ResolveUnixAddr(NetworkUnixgram, t.addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialUnix(NetworkUnixgram, nil, raddr)
+	if err != nil {
+		return err
+	}
+	if t.conn != nil {
+		t.conn.Close()
+	}
+	t.conn = conn
+	return nil
+}
+
+// isReconnectable reports whether err likely indicates that the peer end of
+// the socket has gone away, making a fresh dial worth attempting before
+// giving up. In practice a restarted agent most often surfaces as EPIPE on
+// the next write to the now-dangling connected socket, alongside the more
+// direct ECONNREFUSED/ENOTCONN a still-unconnected or torn-down socket can
+// produce.
+func isReconnectable(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOTCONN) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed)
+}
+
+// Write sends payload as a single datagram, reconnecting once and retrying
+// if the write fails with a reconnectable error.
+func (t *UDSTransport) Write(payload []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.writeTimeout > 0 {
+		t.conn.SetWriteDeadline(time.Now().Add(t.writeTimeout))
+	}
+	_, err := t.conn.Write(payload)
+	if err == nil {
+		return nil
+	}
+	if !isReconnectable(err) {
+		return err
+	}
+	if rerr := t.connect(); rerr != nil {
+		return err
+	}
+	if t.writeTimeout > 0 {
+		t.conn.SetWriteDeadline(time.Now().Add(t.writeTimeout))
+	}
+	_, err = t.conn.Write(payload)
+	return err
+}
+
+// Close releases the underlying socket.
+func (t *UDSTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.conn.Close()
+}
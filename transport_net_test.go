@@ -0,0 +1,116 @@
+package dogstatsd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newUnixgramListener(t *testing.T, path string) *net.UnixConn {
+	addr := &net.UnixAddr{Name: path, Net: NetworkUnixgram}
+	conn, err := net.ListenUnixgram(NetworkUnixgram, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestUDSTransportWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dogstatsd.sock")
+	server := newUnixgramListener(t, path)
+	defer server.Close()
+
+	client, err := NewUDS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.Gauge("test.gauge", 1, nil, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "test.gauge:1.000000|g", string(buf[:n]); want != got {
+		t.Errorf("Expected:\t%s\nActual:\t\t%s", want, got)
+	}
+}
+
+func TestUDSTransportReconnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dogstatsd.sock")
+	server := newUnixgramListener(t, path)
+
+	transport, err := NewUDSTransport(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.Close()
+
+	if err := transport.Write([]byte("before:1|c")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "before:1|c" {
+		t.Fatalf("Expected %q, got %q", "before:1|c", got)
+	}
+
+	// Simulate the agent restarting: the old listener goes away and a new
+	// one is bound at the same path. The next Write should transparently
+	// reconnect rather than surfacing ECONNREFUSED.
+	server.Close()
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	server = newUnixgramListener(t, path)
+	defer server.Close()
+
+	if err := transport.Write([]byte("after:2|c")); err != nil {
+		t.Fatalf("Expected Write to reconnect and succeed, got %v", err)
+	}
+	n, err = server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "after:2|c" {
+		t.Fatalf("Expected %q, got %q", "after:2|c", got)
+	}
+}
+
+func TestUDSTransportWriteTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dogstatsd.sock")
+	server := newUnixgramListener(t, path)
+	defer server.Close()
+
+	// Nobody reads from server, so its receive buffer eventually fills and
+	// blocks further writes until WriteTimeout aborts one.
+	transport, err := NewUDSTransport(path, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.Close()
+
+	payload := make([]byte, 8192)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		err = transport.Write(payload)
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Fatal("Expected Write to eventually time out once the socket buffer fills")
+	}
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Errorf("Expected a net.Error with Timeout() true, got %T: %v", err, err)
+	}
+}
@@ -0,0 +1,259 @@
+package dogstatsd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OTLPOpts configures an OTLPTransport.
+type OTLPOpts struct {
+	// MetricsEndpoint is the OTLP/HTTP metrics endpoint, e.g.
+	// "http://localhost:4318/v1/metrics". Required to export Gauge/Count/
+	// Histogram/Distribution/Timing/Set metrics.
+	MetricsEndpoint string
+
+	// LogsEndpoint is the OTLP/HTTP logs endpoint, e.g.
+	// "http://localhost:4318/v1/logs". Required to export events.
+	LogsEndpoint string
+
+	// HTTPClient is used to make the export requests. http.DefaultClient
+	// is used if nil.
+	HTTPClient *http.Client
+
+	// ResourceAttributes are attached to every exported metric/log record,
+	// e.g. {"service.name": "checkout"}.
+	ResourceAttributes map[string]string
+}
+
+// OTLPTransport translates DogStatsD lines into OTLP metrics and logs and
+// exports them over HTTP, for users migrating off a Datadog agent onto an
+// OpenTelemetry collector. It speaks the OTLP/HTTP+JSON variant of the
+// protocol; a gRPC exporter would require vendoring the OTLP protobuf
+// definitions, which this package does not do.
+//
+// Set metrics have no direct OTLP equivalent (OTLP has no built-in
+// cardinality-counting data point), so they are silently dropped by
+// parseMetricLine rather than exported.
+type OTLPTransport struct {
+	opts OTLPOpts
+}
+
+// NewOTLPTransport returns an OTLPTransport configured from opts.
+func NewOTLPTransport(opts OTLPOpts) *OTLPTransport {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	return &OTLPTransport{opts: opts}
+}
+
+// Write parses payload's lines and exports any metrics to MetricsEndpoint
+// and any events to LogsEndpoint. Unparseable lines are skipped.
+func (t *OTLPTransport) Write(payload []byte) error {
+	var dataPoints []otlpDataPoint
+	var logRecords []otlpLogRecord
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		switch {
+		case bytes.HasPrefix(line, []byte("_e{")):
+			if rec, ok := parseEventLine(string(line)); ok {
+				logRecords = append(logRecords, rec)
+			}
+		case bytes.HasPrefix(line, []byte("_sc|")):
+			if rec, ok := parseServiceCheckLine(string(line)); ok {
+				logRecords = append(logRecords, rec)
+			}
+		default:
+			if dp, ok := parseMetricLine(string(line)); ok {
+				dataPoints = append(dataPoints, dp)
+			}
+		}
+	}
+
+	if len(dataPoints) > 0 && t.opts.MetricsEndpoint != "" {
+		if err := t.postJSON(t.opts.MetricsEndpoint, otlpMetricsRequest(t.opts.ResourceAttributes, dataPoints)); err != nil {
+			return err
+		}
+	}
+	if len(logRecords) > 0 && t.opts.LogsEndpoint != "" {
+		if err := t.postJSON(t.opts.LogsEndpoint, otlpLogsRequest(t.opts.ResourceAttributes, logRecords)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *OTLPTransport) postJSON(endpoint string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := t.opts.HTTPClient.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp export to %s failed: %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Close is a no-op; there is no persistent connection to release.
+func (t *OTLPTransport) Close() error {
+	return nil
+}
+
+type otlpDataPoint struct {
+	name  string
+	value float64
+	tags  []string
+}
+
+type otlpLogRecord struct {
+	body string
+	tags []string
+}
+
+// parseMetricLine parses a "name:value|type[|@rate][|#tags]" DogStatsD
+// line into a data point. The metric type suffix is not preserved; OTLP
+// distinguishes gauges/sums/histograms by shape, which is beyond what this
+// minimal translator attempts. Set metrics (type "s") return ok == false,
+// whatever their value, and are dropped by the caller along with any
+// unparseable line.
+func parseMetricLine(line string) (otlpDataPoint, bool) {
+	nameValue, rest, ok := strings.Cut(line, ":")
+	if !ok {
+		return otlpDataPoint{}, false
+	}
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 || fields[1] == "s" {
+		return otlpDataPoint{}, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return otlpDataPoint{}, false
+	}
+
+	dp := otlpDataPoint{name: nameValue, value: value}
+	for _, field := range fields[2:] {
+		if tags, ok := strings.CutPrefix(field, "#"); ok {
+			dp.tags = strings.Split(tags, ",")
+		}
+	}
+	return dp, true
+}
+
+// parseEventLine parses a "_e{titlelen,textlen}:title|text|..." DogStatsD
+// event into a log record body, dropping the length prefix.
+func parseEventLine(line string) (otlpLogRecord, bool) {
+	_, rest, ok := strings.Cut(line, ":")
+	if !ok {
+		return otlpLogRecord{}, false
+	}
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 {
+		return otlpLogRecord{}, false
+	}
+	rec := otlpLogRecord{body: fmt.Sprintf("%s: %s", fields[0], fields[1])}
+	for _, field := range fields[2:] {
+		if tags, ok := strings.CutPrefix(field, "#"); ok {
+			rec.tags = strings.Split(tags, ",")
+		}
+	}
+	return rec, true
+}
+
+// parseServiceCheckLine parses a "_sc|name|status|..." DogStatsD service
+// check into a log record body.
+func parseServiceCheckLine(line string) (otlpLogRecord, bool) {
+	fields := strings.Split(line, "|")
+	if len(fields) < 3 {
+		return otlpLogRecord{}, false
+	}
+	rec := otlpLogRecord{body: fmt.Sprintf("service check %s: status %s", fields[1], fields[2])}
+	for _, field := range fields[3:] {
+		if tags, ok := strings.CutPrefix(field, "#"); ok {
+			rec.tags = strings.Split(tags, ",")
+		}
+	}
+	return rec, true
+}
+
+func otlpResourceAttrs(attrs map[string]string) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(attrs))
+	for k, v := range attrs {
+		out = append(out, map[string]interface{}{
+			"key":   k,
+			"value": map[string]interface{}{"stringValue": v},
+		})
+	}
+	return out
+}
+
+func otlpTagAttrs(tags []string) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(tags))
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			key, value = tag, ""
+		}
+		out = append(out, map[string]interface{}{
+			"key":   key,
+			"value": map[string]interface{}{"stringValue": value},
+		})
+	}
+	return out
+}
+
+func otlpMetricsRequest(resourceAttrs map[string]string, dataPoints []otlpDataPoint) map[string]interface{} {
+	metrics := make([]map[string]interface{}, len(dataPoints))
+	for i, dp := range dataPoints {
+		metrics[i] = map[string]interface{}{
+			"name": dp.name,
+			"gauge": map[string]interface{}{
+				"dataPoints": []map[string]interface{}{{
+					"asDouble":   dp.value,
+					"attributes": otlpTagAttrs(dp.tags),
+				}},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"resourceMetrics": []map[string]interface{}{{
+			"resource": map[string]interface{}{
+				"attributes": otlpResourceAttrs(resourceAttrs),
+			},
+			"scopeMetrics": []map[string]interface{}{{
+				"scope":   map[string]interface{}{"name": "dogstatsd"},
+				"metrics": metrics,
+			}},
+		}},
+	}
+}
+
+func otlpLogsRequest(resourceAttrs map[string]string, records []otlpLogRecord) map[string]interface{} {
+	logRecords := make([]map[string]interface{}, len(records))
+	for i, rec := range records {
+		logRecords[i] = map[string]interface{}{
+			"body":       map[string]interface{}{"stringValue": rec.body},
+			"attributes": otlpTagAttrs(rec.tags),
+		}
+	}
+	return map[string]interface{}{
+		"resourceLogs": []map[string]interface{}{{
+			"resource": map[string]interface{}{
+				"attributes": otlpResourceAttrs(resourceAttrs),
+			},
+			"scopeLogs": []map[string]interface{}{{
+				"scope":      map[string]interface{}{"name": "dogstatsd"},
+				"logRecords": logRecords,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,120 @@
+package dogstatsd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOTLPTransportWriteMetrics(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := NewOTLPTransport(OTLPOpts{
+		MetricsEndpoint:    server.URL,
+		ResourceAttributes: map[string]string{"service.name": "checkout"},
+	})
+	defer transport.Close()
+
+	if err := transport.Write([]byte("test.gauge:1.000000|g|#tagA:1")); err != nil {
+		t.Fatal(err)
+	}
+
+	resourceMetrics, ok := got["resourceMetrics"].([]interface{})
+	if !ok || len(resourceMetrics) != 1 {
+		t.Fatalf("Expected one resourceMetrics entry, got %#v", got["resourceMetrics"])
+	}
+	resource := resourceMetrics[0].(map[string]interface{})["resource"].(map[string]interface{})
+	attrs := resource["attributes"].([]interface{})
+	if len(attrs) != 1 {
+		t.Fatalf("Expected one resource attribute, got %#v", attrs)
+	}
+	attr := attrs[0].(map[string]interface{})
+	if attr["key"] != "service.name" {
+		t.Errorf("Expected resource attribute key %q, got %q", "service.name", attr["key"])
+	}
+
+	scopeMetrics := resourceMetrics[0].(map[string]interface{})["scopeMetrics"].([]interface{})
+	metrics := scopeMetrics[0].(map[string]interface{})["metrics"].([]interface{})
+	if len(metrics) != 1 {
+		t.Fatalf("Expected one metric, got %#v", metrics)
+	}
+	metric := metrics[0].(map[string]interface{})
+	if metric["name"] != "test.gauge" {
+		t.Errorf("Expected metric name %q, got %q", "test.gauge", metric["name"])
+	}
+	dataPoints := metric["gauge"].(map[string]interface{})["dataPoints"].([]interface{})
+	dp := dataPoints[0].(map[string]interface{})
+	if dp["asDouble"] != 1.0 {
+		t.Errorf("Expected asDouble 1.0, got %v", dp["asDouble"])
+	}
+}
+
+func TestOTLPTransportWriteDropsSets(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := NewOTLPTransport(OTLPOpts{MetricsEndpoint: server.URL})
+	defer transport.Close()
+
+	if err := transport.Write([]byte("test.set:some-uuid|s")); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected Set metric to be dropped with no request sent, got %d requests", requests)
+	}
+}
+
+func TestOTLPTransportWriteEvent(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := NewOTLPTransport(OTLPOpts{LogsEndpoint: server.URL})
+	defer transport.Close()
+
+	if err := transport.Write([]byte("_e{5,4}:title|text|#tagA")); err != nil {
+		t.Fatal(err)
+	}
+
+	resourceLogs := got["resourceLogs"].([]interface{})
+	scopeLogs := resourceLogs[0].(map[string]interface{})["scopeLogs"].([]interface{})
+	logRecords := scopeLogs[0].(map[string]interface{})["logRecords"].([]interface{})
+	if len(logRecords) != 1 {
+		t.Fatalf("Expected one log record, got %#v", logRecords)
+	}
+	body := logRecords[0].(map[string]interface{})["body"].(map[string]interface{})["stringValue"]
+	if body != "title: text" {
+		t.Errorf("Expected body %q, got %q", "title: text", body)
+	}
+}
+
+func TestOTLPTransportWriteErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	transport := NewOTLPTransport(OTLPOpts{MetricsEndpoint: server.URL})
+	defer transport.Close()
+
+	if err := transport.Write([]byte("test.gauge:1.000000|g")); err == nil {
+		t.Error("Expected an error for a non-2xx response, got nil")
+	}
+}